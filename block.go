@@ -0,0 +1,211 @@
+package warc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/textproto"
+)
+
+// Block is the content block of a Record. Implementations read their
+// underlying bytes lazily, so a Record whose block has not yet been
+// consumed does not hold its payload in memory. Callers that need to
+// read a block more than once should call Cache first.
+type Block interface {
+	// RawBytes returns a reader over the full, unmodified content block.
+	RawBytes() (io.ReadCloser, error)
+	// PayloadBytes returns a reader over the block's payload. For an
+	// "application/http" block this strips the HTTP status/request line
+	// and headers, returning only the entity body. For every other
+	// block type the payload is the same as RawBytes.
+	PayloadBytes() (io.ReadCloser, error)
+	// BlockDigest returns the WARC-Block-Digest value associated with
+	// this block, in "algorithm:value" form, or "" if none is known.
+	BlockDigest() string
+	// PayloadDigest returns the WARC-Payload-Digest value associated
+	// with this block, in "algorithm:value" form, or "" if none is known.
+	PayloadDigest() string
+	// Size returns the length of the block in bytes, as would be
+	// reported by the WARC-Content-Length header.
+	Size() int64
+	// Cache reads the block fully into memory so that subsequent calls
+	// to RawBytes and PayloadBytes can be repeated. Blocks read directly
+	// off a Reader can only be consumed once unless Cache is called
+	// first.
+	Cache() error
+}
+
+// opener produces a reader over a block's raw bytes. For a block read
+// off a Reader it returns the same single-use stream on every call;
+// once the block has been cached, RawBytes reads from the cache and no
+// longer calls the opener. Blocks constructed directly from a []byte
+// have no opener and always read from that slice.
+type opener func() (io.Reader, error)
+
+// genericBlock is the Block implementation used for record types whose
+// payload is simply the block itself (resource, metadata,
+// warc-fields, revisit, etc.).
+type genericBlock struct {
+	open          opener
+	size          int64
+	blockDigest   string
+	payloadDigest string
+	cached        []byte
+}
+
+// newGenericBlock builds a Block around an opener function that is
+// called to obtain a reader over the block's bytes.
+func newGenericBlock(open opener, size int64, blockDigest, payloadDigest string) *genericBlock {
+	return &genericBlock{open: open, size: size, blockDigest: blockDigest, payloadDigest: payloadDigest}
+}
+
+// newBytesBlock builds a Block over an in-memory byte slice. Useful for
+// records constructed programmatically rather than read from a stream.
+func newBytesBlock(b []byte, blockDigest, payloadDigest string) *genericBlock {
+	blk := newGenericBlock(nil, int64(len(b)), blockDigest, payloadDigest)
+	blk.cached = b
+	return blk
+}
+
+func (b *genericBlock) RawBytes() (io.ReadCloser, error) {
+	if b.cached != nil {
+		return ioutil.NopCloser(bytes.NewReader(b.cached)), nil
+	}
+	if b.open == nil {
+		return ioutil.NopCloser(bytes.NewReader(nil)), nil
+	}
+	r, err := b.open()
+	if err != nil {
+		return nil, err
+	}
+	if rc, ok := r.(io.ReadCloser); ok {
+		return rc, nil
+	}
+	return ioutil.NopCloser(r), nil
+}
+
+func (b *genericBlock) PayloadBytes() (io.ReadCloser, error) {
+	return b.RawBytes()
+}
+
+func (b *genericBlock) BlockDigest() string   { return b.blockDigest }
+func (b *genericBlock) PayloadDigest() string { return b.payloadDigest }
+func (b *genericBlock) Size() int64           { return b.size }
+
+func (b *genericBlock) Cache() error {
+	if b.cached != nil {
+		return nil
+	}
+	rc, err := b.RawBytes()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	buf, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return err
+	}
+	b.cached = buf
+	return nil
+}
+
+// httpResponseBlock is the Block implementation for records with
+// Content-Type "application/http; msgtype=response". Its payload is the
+// HTTP entity body, parsed on demand from the raw block.
+type httpResponseBlock struct {
+	*genericBlock
+}
+
+func newHTTPResponseBlock(open opener, size int64, blockDigest, payloadDigest string) *httpResponseBlock {
+	return &httpResponseBlock{newGenericBlock(open, size, blockDigest, payloadDigest)}
+}
+
+func (b *httpResponseBlock) PayloadBytes() (io.ReadCloser, error) {
+	raw, err := b.RawBytes()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.ReadResponse(newBufioReader(raw), nil)
+	if err != nil {
+		raw.Close()
+		return nil, fmt.Errorf("parsing http response block: %s", err.Error())
+	}
+	return &chainReadCloser{Reader: resp.Body, closers: []io.Closer{resp.Body, raw}}, nil
+}
+
+// httpRequestBlock is the Block implementation for records with
+// Content-Type "application/http; msgtype=request". Its payload is the
+// HTTP request body, parsed on demand from the raw block.
+type httpRequestBlock struct {
+	*genericBlock
+}
+
+func newHTTPRequestBlock(open opener, size int64, blockDigest, payloadDigest string) *httpRequestBlock {
+	return &httpRequestBlock{newGenericBlock(open, size, blockDigest, payloadDigest)}
+}
+
+func (b *httpRequestBlock) PayloadBytes() (io.ReadCloser, error) {
+	raw, err := b.RawBytes()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.ReadRequest(newBufioReader(raw))
+	if err != nil {
+		raw.Close()
+		return nil, fmt.Errorf("parsing http request block: %s", err.Error())
+	}
+	return &chainReadCloser{Reader: req.Body, closers: []io.Closer{req.Body, raw}}, nil
+}
+
+// warcFieldsBlock is the Block implementation for records with
+// Content-Type "application/warc-fields", such as warcinfo and some
+// metadata records. Its payload is the block itself.
+type warcFieldsBlock struct {
+	*genericBlock
+}
+
+func newWarcFieldsBlock(open opener, size int64, blockDigest, payloadDigest string) *warcFieldsBlock {
+	return &warcFieldsBlock{newGenericBlock(open, size, blockDigest, payloadDigest)}
+}
+
+// Fields parses the block as a sequence of "name: value" lines, the
+// format used by application/warc-fields blocks.
+func (b *warcFieldsBlock) Fields() (map[string]string, error) {
+	rc, err := b.RawBytes()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	tp := textproto.NewReader(newBufioReader(rc))
+	header, err := tp.ReadMIMEHeader()
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	fields := make(map[string]string, len(header))
+	for k, v := range header {
+		if len(v) > 0 {
+			fields[k] = v[0]
+		}
+	}
+	return fields, nil
+}
+
+// chainReadCloser reads from Reader and, on Close, closes every closer
+// in order. Used to make sure parsing an http.Response/http.Request out
+// of a block's raw reader doesn't leak the underlying reader.
+type chainReadCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (c *chainReadCloser) Close() error {
+	var err error
+	for _, cl := range c.closers {
+		if cerr := cl.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
@@ -0,0 +1,339 @@
+// Package cdx generates CDX and CDXJ indexes over a WARC stream, the
+// de-facto lookup formats used by pywb, OpenWayback, and the rest of
+// the IIPC tool ecosystem to locate a capture by URL and time without
+// scanning the whole WARC file.
+package cdx
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/machawk1/warc"
+)
+
+// Format selects the index format a Generator writes.
+type Format int
+
+const (
+	// FormatCDX11 is the classic 11-field CDX format, with a leading
+	// header line declaring field order.
+	FormatCDX11 Format = iota
+	// FormatCDXJ is "SURT timestamp {json}" per line.
+	FormatCDXJ
+)
+
+// cdx11Header is the field-order header line classic CDX readers
+// expect as the first line of the file.
+const cdx11Header = " CDX N b a m s k r M S V g"
+
+// Entry is a single parsed index line: one WARC response/resource/
+// revisit record, reduced to the fields a CDX lookup needs.
+type Entry struct {
+	SURT           string // canonicalized SURT form of the target URI
+	Timestamp      string // WARC-Date, reformatted as 14-digit YYYYMMDDHHMMSS
+	OriginalURI    string
+	MIMEType       string
+	StatusCode     string
+	PayloadDigest  string
+	RedirectTarget string
+	Offset         int64
+	Length         int64
+	Filename       string
+}
+
+// RecordSource is the subset of *warc.Reader a Generator needs: a
+// stream of records, each tagged with the byte offset it began at.
+type RecordSource interface {
+	Read() (*warc.Record, error)
+	Offset() int64
+}
+
+// Generator reads records from a RecordSource and writes one index
+// line per response/resource/revisit record to an underlying writer.
+type Generator struct {
+	w        io.Writer
+	format   Format
+	filename string
+	wroteHdr bool
+}
+
+// NewGenerator builds a Generator that writes the given format to w.
+// filename is recorded on every entry (the "g" field in CDX11, the
+// "filename" field in CDXJ) and is typically the name of the source
+// WARC file, since offsets alone aren't useful without knowing which
+// file they're relative to.
+func NewGenerator(w io.Writer, format Format, filename string) *Generator {
+	return &Generator{w: w, format: format, filename: filename}
+}
+
+// Generate reads every record from src and writes its index line. It
+// does not close src itself.
+func (g *Generator) Generate(src RecordSource) error {
+	if g.format == FormatCDX11 && !g.wroteHdr {
+		if _, err := fmt.Fprintln(g.w, cdx11Header); err != nil {
+			return err
+		}
+		g.wroteHdr = true
+	}
+
+	compSrc, compressed := src.(compressedRangeReporter)
+	if compressed && compSrc.CompressedOffset() < 0 {
+		// *warc.Reader reports -1 for a stream that isn't gzipped.
+		compressed = false
+	}
+
+	var pending *Entry
+	var pendingOffset int64
+
+	flush := func(length int64) error {
+		if pending == nil {
+			return nil
+		}
+		// A gzip-backed source already reports its own compressed
+		// length per record; only a plain stream needs its length
+		// inferred from where the next record began.
+		if !compressed {
+			pending.Length = length
+		}
+		return g.writeEntry(*pending)
+	}
+
+	for {
+		rec, err := src.Read()
+		offset := src.Offset()
+		if err == io.EOF {
+			return flush(finalLength(src, pendingOffset))
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := flush(offset - pendingOffset); err != nil {
+			return err
+		}
+		pending = nil
+
+		entry, err := g.buildEntry(rec, offset)
+		if err != nil {
+			return err
+		}
+		if entry != nil {
+			if compressed {
+				// Point the entry at the compressed byte range of the
+				// gzip member the record came from, the range a CDX
+				// reader needs to seek directly into a .warc.gz file
+				// without decompressing the whole thing.
+				entry.Offset = compSrc.CompressedOffset()
+				entry.Length = compSrc.CompressedLength()
+			}
+			pending = entry
+			pendingOffset = offset
+		} else {
+			// Not an indexable record type; the next indexable record's
+			// length should still be measured from its own offset, not
+			// this one's, so there's nothing to carry forward.
+			pendingOffset = offset
+		}
+	}
+}
+
+// compressedRangeReporter is implemented by a gzip-backed RecordSource
+// (see warc.GzipReader), reporting the compressed byte range of the
+// gzip member containing the most recently returned record. Generate
+// type-asserts for it, the same way finalLength does for Pos, and uses
+// it in place of the uncompressed offset/length when available. A
+// source reporting a negative CompressedOffset is treated as plain.
+type compressedRangeReporter interface {
+	CompressedOffset() int64
+	CompressedLength() int64
+}
+
+// finalLength reports the length of the final record in the stream, if
+// src can report the overall stream length; otherwise 0.
+func finalLength(src RecordSource, pendingOffset int64) int64 {
+	type totalLenReporter interface{ Pos() int64 }
+	if r, ok := src.(totalLenReporter); ok {
+		return r.Pos() - pendingOffset
+	}
+	return 0
+}
+
+// indexableTypes are the WARC record types a CDX entry is generated
+// for.
+var indexableTypes = map[warc.RecordType]bool{
+	warc.RecordTypeResponse: true,
+	warc.RecordTypeResource: true,
+	warc.RecordTypeRevisit:  true,
+}
+
+func (g *Generator) buildEntry(rec *warc.Record, offset int64) (*Entry, error) {
+	if !indexableTypes[rec.Type()] {
+		return nil, nil
+	}
+
+	uri := rec.Headers["WARC-Target-URI"]
+	surt, err := CanonicalizeSURT(uri)
+	if err != nil {
+		return nil, fmt.Errorf("canonicalizing %q: %s", uri, err.Error())
+	}
+
+	entry := &Entry{
+		SURT:        surt,
+		Timestamp:   toCDXTimestamp(rec.Date()),
+		OriginalURI: uri,
+		MIMEType:    "unk",
+		StatusCode:  "-",
+		Offset:      offset,
+		Filename:    g.filename,
+	}
+
+	entry.PayloadDigest = rec.Headers["WARC-Payload-Digest"]
+
+	if ct := strings.ToLower(rec.Headers["Content-Type"]); strings.HasPrefix(ct, "application/http") {
+		if resp, err := rec.HTTPResponse(); err == nil {
+			entry.StatusCode = strconv.Itoa(resp.StatusCode)
+			if mt := resp.Header.Get("Content-Type"); mt != "" {
+				if i := strings.Index(mt, ";"); i >= 0 {
+					mt = mt[:i]
+				}
+				entry.MIMEType = strings.TrimSpace(mt)
+			}
+			if loc := resp.Header.Get("Location"); loc != "" {
+				entry.RedirectTarget = loc
+			}
+			resp.Body.Close()
+		}
+	}
+
+	return entry, nil
+}
+
+func (g *Generator) writeEntry(e Entry) error {
+	switch g.format {
+	case FormatCDXJ:
+		return g.writeCDXJ(e)
+	default:
+		return g.writeCDX11(e)
+	}
+}
+
+func (g *Generator) writeCDX11(e Entry) error {
+	redirect := e.RedirectTarget
+	if redirect == "" {
+		redirect = "-"
+	}
+	digest := e.PayloadDigest
+	if digest == "" {
+		digest = "-"
+	}
+	fields := []string{
+		e.SURT,
+		e.Timestamp,
+		e.OriginalURI,
+		e.MIMEType,
+		e.StatusCode,
+		digest,
+		redirect,
+		"-", // M: meta tags, unused by this generator
+		strconv.FormatInt(e.Length, 10),
+		strconv.FormatInt(e.Offset, 10),
+		e.Filename,
+	}
+	_, err := fmt.Fprintln(g.w, strings.Join(fields, " "))
+	return err
+}
+
+func (g *Generator) writeCDXJ(e Entry) error {
+	obj := map[string]interface{}{
+		"url":      e.OriginalURI,
+		"mime":     e.MIMEType,
+		"status":   e.StatusCode,
+		"digest":   e.PayloadDigest,
+		"length":   strconv.FormatInt(e.Length, 10),
+		"offset":   strconv.FormatInt(e.Offset, 10),
+		"filename": e.Filename,
+	}
+	if e.RedirectTarget != "" {
+		obj["redirect"] = e.RedirectTarget
+	}
+	line, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(g.w, "%s %s %s\n", e.SURT, e.Timestamp, line)
+	return err
+}
+
+// toCDXTimestamp reformats a time.Time as the 14-digit YYYYMMDDHHMMSS
+// timestamp CDX entries use.
+func toCDXTimestamp(t time.Time) string {
+	return t.Format("20060102150405")
+}
+
+// Sort reads every line from r (as written by a Generator) and writes
+// them back to w, ordered by SURT key and then timestamp, the order
+// CDX lookups expect.
+func Sort(w io.Writer, r io.Reader) error {
+	lines, header, err := readLines(r)
+	if err != nil {
+		return err
+	}
+	sort.Strings(lines)
+	return writeLines(w, header, lines)
+}
+
+// Merge combines any number of already-sorted CDX streams into one,
+// preserving overall SURT/timestamp order the way a k-way merge of
+// per-shard indexes should.
+func Merge(w io.Writer, readers ...io.Reader) error {
+	var all []string
+	var header string
+	for _, r := range readers {
+		lines, hdr, err := readLines(r)
+		if err != nil {
+			return err
+		}
+		if hdr != "" {
+			header = hdr
+		}
+		all = append(all, lines...)
+	}
+	sort.Strings(all)
+	return writeLines(w, header, all)
+}
+
+func readLines(r io.Reader) (lines []string, header string, err error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, " CDX") {
+			header = line
+			continue
+		}
+		if line == "" {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, header, scanner.Err()
+}
+
+func writeLines(w io.Writer, header string, lines []string) error {
+	if header != "" {
+		if _, err := fmt.Fprintln(w, header); err != nil {
+			return err
+		}
+	}
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package cdx
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/machawk1/warc"
+)
+
+func TestCanonicalizeSURT(t *testing.T) {
+	cases := map[string]string{
+		"http://example.com/":              "com,example)/",
+		"http://www.example.com/a?b=1&a=2": "com,example,www)/a?a=2&b=1",
+		"dns:google.com":                   "com,google)/",
+	}
+	for in, want := range cases {
+		got, err := CanonicalizeSURT(in)
+		if err != nil {
+			t.Errorf("CanonicalizeSURT(%q) returned error: %s", in, err.Error())
+			continue
+		}
+		if got != want {
+			t.Errorf("CanonicalizeSURT(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestGenerateCDX11(t *testing.T) {
+	body := "<html></html>"
+	resp := &http.Response{
+		StatusCode:    200,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"text/html"}},
+		Body:          ioutil.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+	}
+
+	rec, err := warc.NewHTTPResponseRecord("http://example.com/", resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var warcFile bytes.Buffer
+	if err := warc.WriteRecords(&warcFile, warc.Records{*rec}); err != nil {
+		t.Fatal(err)
+	}
+
+	rdr, err := warc.NewReader(bytes.NewReader(warcFile.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	gen := NewGenerator(&out, FormatCDX11, "test.warc")
+	if err := gen.Generate(rdr); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimRight(out.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected a header line and one entry, got %d lines: %q", len(lines), out.String())
+	}
+	if lines[0] != cdx11Header {
+		t.Errorf("unexpected CDX header: %q", lines[0])
+	}
+
+	fields := strings.Fields(lines[1])
+	if len(fields) != 11 {
+		t.Fatalf("expected 11 CDX fields, got %d: %q", len(fields), lines[1])
+	}
+	if fields[0] != "com,example)/" {
+		t.Errorf("unexpected SURT key: %q", fields[0])
+	}
+	if fields[4] != "200" {
+		t.Errorf("unexpected status field: %q", fields[4])
+	}
+	if fields[3] != "text/html" {
+		t.Errorf("unexpected mime field: %q", fields[3])
+	}
+}
+
+func TestGenerateCDXGzipUsesCompressedRange(t *testing.T) {
+	bodies := []string{"<html>one</html>", "<html>two</html>"}
+	var recs warc.Records
+	for i, body := range bodies {
+		resp := &http.Response{
+			StatusCode:    200,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{"Content-Type": []string{"text/html"}},
+			Body:          ioutil.NopCloser(strings.NewReader(body)),
+			ContentLength: int64(len(body)),
+		}
+		rec, err := warc.NewHTTPResponseRecord(fmt.Sprintf("http://example.com/%d", i), resp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		recs = append(recs, *rec)
+	}
+
+	var gzFile bytes.Buffer
+	gw := warc.NewGzipWriter(&gzFile)
+	if err := gw.WriteRecords(recs); err != nil {
+		t.Fatal(err)
+	}
+
+	// Read independently, off a fresh Reader, to learn the actual
+	// compressed byte range of each record's gzip member.
+	wantRdr, err := warc.NewReader(bytes.NewReader(gzFile.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wantOffsets, wantLengths []int64
+	for range recs {
+		if _, err := wantRdr.Read(); err != nil {
+			t.Fatal(err)
+		}
+		wantOffsets = append(wantOffsets, wantRdr.CompressedOffset())
+		wantLengths = append(wantLengths, wantRdr.CompressedLength())
+	}
+
+	rdr, err := warc.NewReader(bytes.NewReader(gzFile.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	gen := NewGenerator(&out, FormatCDX11, "test.warc.gz")
+	if err := gen.Generate(rdr); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimRight(out.String(), "\n"), "\n")
+	if len(lines) != 1+len(recs) {
+		t.Fatalf("expected a header line and %d entries, got %d lines: %q", len(recs), len(lines), out.String())
+	}
+
+	for i, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if len(fields) != 11 {
+			t.Fatalf("expected 11 CDX fields, got %d: %q", len(fields), line)
+		}
+		gotLength, err := strconv.ParseInt(fields[8], 10, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gotOffset, err := strconv.ParseInt(fields[9], 10, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if gotOffset != wantOffsets[i] {
+			t.Errorf("entry %d offset = %d, want %d (compressed)", i, gotOffset, wantOffsets[i])
+		}
+		if gotLength != wantLengths[i] {
+			t.Errorf("entry %d length = %d, want %d (compressed)", i, gotLength, wantLengths[i])
+		}
+	}
+}
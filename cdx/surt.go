@@ -0,0 +1,67 @@
+package cdx
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// CanonicalizeSURT converts a URI into SURT (Sort-friendly URI
+// Reordering Transform) form, eg "http://example.com/a?b=1" becomes
+// "com,example)/a?b=1". Hostname labels are reversed and lowercased,
+// and query parameters are sorted by key so that equivalent URLs with
+// differently-ordered query strings canonicalize to the same key.
+func CanonicalizeSURT(raw string) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("parsing uri: %s", err.Error())
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if host == "" {
+		// Non-hierarchical schemes (eg "dns:google.com") carry their
+		// identifying token as the opaque part rather than a host.
+		host = strings.ToLower(u.Opaque)
+	}
+	if host == "" {
+		return "", fmt.Errorf("no host or opaque component in %q", raw)
+	}
+
+	labels := strings.Split(host, ".")
+	for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+		labels[i], labels[j] = labels[j], labels[i]
+	}
+
+	surt := strings.Join(labels, ",") + ")"
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	surt += path
+
+	if u.RawQuery != "" {
+		surt += "?" + canonicalizeQuery(u.Query())
+	}
+
+	return surt, nil
+}
+
+// canonicalizeQuery renders a URL's query parameters sorted by key, so
+// that "?b=2&a=1" and "?a=1&b=2" canonicalize identically.
+func canonicalizeQuery(values url.Values) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(values))
+	for _, k := range keys {
+		for _, v := range values[k] {
+			parts = append(parts, k+"="+v)
+		}
+	}
+	return strings.Join(parts, "&")
+}
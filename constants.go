@@ -0,0 +1,27 @@
+package warc
+
+// Canonical WARC header field names. These are used as keys into a
+// Record's Headers map. The WARC spec treats header names
+// case-insensitively on the wire (see Reader), but records are always
+// normalized to this casing internally.
+const (
+	warc_type                 = "WARC-Type"
+	warc_record_id            = "WARC-Record-ID"
+	warc_date                 = "WARC-Date"
+	warc_target_uri           = "WARC-Target-URI"
+	warc_filename             = "WARC-Filename"
+	warc_refers_to            = "WARC-Refers-To"
+	warc_refers_to_target_uri = "WARC-Refers-To-Target-URI"
+	warc_refers_to_date       = "WARC-Refers-To-Date"
+	warc_concurrent_to        = "WARC-Concurrent-To"
+	warc_block_digest         = "WARC-Block-Digest"
+	warc_payload_digest       = "WARC-Payload-Digest"
+	warc_ip_address           = "WARC-IP-Address"
+	warc_profile              = "WARC-Profile"
+	warc_segment_number       = "WARC-Segment-Number"
+	warc_segment_origin_id    = "WARC-Segment-Origin-ID"
+	warc_segment_total_length = "WARC-Segment-Total-Length"
+
+	content_type   = "Content-Type"
+	content_length = "Content-Length"
+)
@@ -0,0 +1,226 @@
+package warc
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+)
+
+// defaultDigestAlgorithm is the algorithm used by Record.Write to fill
+// in missing WARC-Block-Digest/WARC-Payload-Digest headers.
+const defaultDigestAlgorithm = "sha1"
+
+// digestAlgorithm describes a hash algorithm usable for
+// WARC-Block-Digest / WARC-Payload-Digest values: a factory that
+// produces a fresh hash.Hash, and an encoder that renders a digest sum
+// the way this algorithm is conventionally written in a WARC file.
+type digestAlgorithm struct {
+	factory func() hash.Hash
+	encode  func([]byte) string
+}
+
+// digestAlgorithms holds every algorithm this package knows how to
+// compute or verify, keyed by the lowercased name used in a WARC
+// digest's "algorithm:value" form (eg "sha1", "sha256").
+var digestAlgorithms = map[string]digestAlgorithm{
+	"sha1": {
+		factory: sha1.New,
+		encode:  func(sum []byte) string { return base32.StdEncoding.EncodeToString(sum) },
+	},
+	"sha256": {
+		factory: sha256.New,
+		encode:  func(sum []byte) string { return hex.EncodeToString(sum) },
+	},
+}
+
+// RegisterDigestAlgorithm adds or replaces the hash algorithm used for
+// the given name (eg "md5", "blake2b") when computing or verifying
+// digests. name is matched case-insensitively against the algorithm
+// token in a WARC-Block-Digest/WARC-Payload-Digest header.
+func RegisterDigestAlgorithm(name string, factory func() hash.Hash, encode func([]byte) string) {
+	digestAlgorithms[strings.ToLower(name)] = digestAlgorithm{factory: factory, encode: encode}
+}
+
+// splitDigest splits a WARC digest header value of the form
+// "algorithm:value" into its two parts. ok is false if s isn't in that
+// form or names an unregistered algorithm.
+func splitDigest(s string) (alg digestAlgorithm, name, value string, ok bool) {
+	i := strings.Index(s, ":")
+	if i < 0 {
+		return digestAlgorithm{}, "", "", false
+	}
+	name = strings.ToLower(s[:i])
+	value = s[i+1:]
+	alg, ok = digestAlgorithms[name]
+	return alg, name, value, ok
+}
+
+// computeDigest hashes the full content of r with the named algorithm,
+// returning a header value in "algorithm:value" form.
+func computeDigest(name string, r io.Reader) (string, error) {
+	alg, ok := digestAlgorithms[strings.ToLower(name)]
+	if !ok {
+		return "", fmt.Errorf("unknown digest algorithm %q", name)
+	}
+	h := alg.factory()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s:%s", strings.ToLower(name), alg.encode(h.Sum(nil))), nil
+}
+
+// digestsEqual compares two WARC digest header values for equality.
+// Rather than comparing the encoded values as strings, it decodes each
+// to its raw digest bytes first, since archives in the wild encode the
+// same algorithm's digest using either hex or base32 (sha1 digests are
+// conventionally base32, but sha256 ones are seen in both forms) and a
+// digest header authored with one encoding should still validate
+// against a value computed with the other. It falls back to a
+// case-insensitive string comparison if either value isn't in
+// recognizable "algorithm:value" form.
+func digestsEqual(a, b string) bool {
+	_, nameA, valueA, okA := splitDigest(a)
+	_, nameB, valueB, okB := splitDigest(b)
+	if okA && okB && nameA == nameB {
+		rawA, errA := decodeDigestValue(valueA)
+		rawB, errB := decodeDigestValue(valueB)
+		if errA == nil && errB == nil {
+			return bytes.Equal(rawA, rawB)
+		}
+	}
+	return strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b))
+}
+
+// decodeDigestValue decodes a digest's encoded value, trying the two
+// encodings this package emits (hex and base32) in turn.
+func decodeDigestValue(value string) ([]byte, error) {
+	if raw, err := hex.DecodeString(value); err == nil {
+		return raw, nil
+	}
+	return base32.StdEncoding.DecodeString(strings.ToUpper(value))
+}
+
+// ValidateDigests recomputes the record's block digest, and its
+// payload digest where one is present, and compares them against the
+// WARC-Block-Digest and WARC-Payload-Digest headers. It returns an
+// error describing the first mismatch found, or nil if every digest
+// present on the record checks out. A record with no digest headers at
+// all is considered valid, since there is nothing to check.
+//
+// Checking both digests reads the block twice (once via RawBytes, once
+// via PayloadBytes), so, like Record.Write, ValidateDigests caches the
+// block first.
+func (r *Record) ValidateDigests() error {
+	if r.Content != nil {
+		if err := r.Content.Cache(); err != nil {
+			return fmt.Errorf("caching content block: %s", err.Error())
+		}
+	}
+
+	if want := r.Headers[warc_block_digest]; want != "" {
+		got, err := r.computeBlockDigest()
+		if err != nil {
+			return fmt.Errorf("computing block digest: %s", err.Error())
+		}
+		if !digestsEqual(want, got) {
+			return fmt.Errorf("block digest mismatch: header says %s, computed %s", want, got)
+		}
+	}
+
+	if want := r.Headers[warc_payload_digest]; want != "" {
+		got, err := r.computePayloadDigest()
+		if err != nil {
+			return fmt.Errorf("computing payload digest: %s", err.Error())
+		}
+		if !digestsEqual(want, got) {
+			return fmt.Errorf("payload digest mismatch: header says %s, computed %s", want, got)
+		}
+	}
+
+	return nil
+}
+
+func (r *Record) computeBlockDigest() (string, error) {
+	alg, name, _, ok := splitDigest(r.Headers[warc_block_digest])
+	if !ok {
+		return "", fmt.Errorf("unsupported digest algorithm in %q", r.Headers[warc_block_digest])
+	}
+	rc, err := r.Content.RawBytes()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	h := alg.factory()
+	if _, err := io.Copy(h, rc); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s:%s", name, alg.encode(h.Sum(nil))), nil
+}
+
+func (r *Record) computePayloadDigest() (string, error) {
+	alg, name, _, ok := splitDigest(r.Headers[warc_payload_digest])
+	if !ok {
+		return "", fmt.Errorf("unsupported digest algorithm in %q", r.Headers[warc_payload_digest])
+	}
+	rc, err := r.Content.PayloadBytes()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	h := alg.factory()
+	if _, err := io.Copy(h, rc); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s:%s", name, alg.encode(h.Sum(nil))), nil
+}
+
+// ensureDigests fills in WARC-Block-Digest and WARC-Payload-Digest on r
+// using algorithm if they aren't already present, computing each from
+// its own reader over the block (RawBytes for the block digest,
+// PayloadBytes for the payload digest). Since the WARC format requires
+// these headers to precede the block they describe, the caller must
+// have cached the content block first (see Record.Write) if it needs
+// to read the block again afterward. algorithm names a registered
+// digest algorithm, eg "sha1" or "sha256".
+func (r *Record) ensureDigests(algorithm string) error {
+	if r.Content == nil {
+		return nil
+	}
+
+	if r.Headers[warc_block_digest] == "" {
+		rc, err := r.Content.RawBytes()
+		if err != nil {
+			return err
+		}
+		digest, err := computeDigest(algorithm, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+		r.Headers[warc_block_digest] = digest
+	}
+
+	// A continuation record's payload digest describes the reassembled
+	// whole, not any individual segment (see SegmentingWriter), so it
+	// has no payload digest of its own to fill in.
+	if r.Headers[warc_payload_digest] == "" && r.Type() != RecordTypeContinuation {
+		rc, err := r.Content.PayloadBytes()
+		if err != nil {
+			return err
+		}
+		digest, err := computeDigest(algorithm, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+		r.Headers[warc_payload_digest] = digest
+	}
+
+	return nil
+}
@@ -0,0 +1,151 @@
+package warc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+)
+
+// gzipMagic0 and gzipMagic1 are the two leading bytes of every gzip
+// stream, used by NewReader to decide whether to switch into
+// per-record gzip member parsing.
+const (
+	gzipMagic0 = 0x1f
+	gzipMagic1 = 0x8b
+)
+
+// GzipWriter wraps an io.Writer, writing each record as its own,
+// independently-decodable gzip member. This is what the WARC 1.0 spec
+// requires of a ".warc.gz" file: a straight concatenation of gzip
+// members, one per record, so that a record can be located and
+// decompressed via a CDX offset without decompressing the whole file.
+type GzipWriter struct {
+	w io.Writer
+}
+
+// NewGzipWriter constructs a GzipWriter writing to w.
+func NewGzipWriter(w io.Writer) *GzipWriter {
+	return &GzipWriter{w: w}
+}
+
+// WriteRecord gzip-compresses r into its own member and writes it to
+// the underlying writer.
+func (gw *GzipWriter) WriteRecord(r *Record) error {
+	gzw := gzip.NewWriter(gw.w)
+	if err := r.Write(gzw); err != nil {
+		gzw.Close()
+		return err
+	}
+	return gzw.Close()
+}
+
+// WriteRecords writes each record in recs as its own gzip member, in
+// order.
+func (gw *GzipWriter) WriteRecords(recs Records) error {
+	for i := range recs {
+		if err := gw.WriteRecord(&recs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GzipReader reads a ".warc.gz" stream one gzip member at a time,
+// decoding exactly one Record per member and tracking both the
+// uncompressed offset (as Reader.Offset does for a plain WARC stream)
+// and the compressed offset/length of the member it came from, which a
+// CDX index needs to later seek directly to that record.
+type GzipReader struct {
+	cr              *countingReader
+	br              *byteReader
+	uncompressedPos int64
+
+	offset           int64
+	compressedOffset int64
+	compressedLength int64
+}
+
+// NewGzipReader constructs a GzipReader reading members from r.
+func NewGzipReader(r io.Reader) (*GzipReader, error) {
+	cr := &countingReader{r: r}
+	return &GzipReader{cr: cr, br: &byteReader{r: cr}}, nil
+}
+
+// Read decodes the next gzip member and parses the single Record it
+// contains.
+func (gr *GzipReader) Read() (*Record, error) {
+	compStart := gr.cr.n
+
+	gzr, err := gzip.NewReader(gr.br)
+	if err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	// gzip.Reader defaults to decoding concatenated gzip members as one
+	// continuous stream. A .warc.gz file's whole point is one
+	// independently-decodable member per record, so without this,
+	// ReadAll would silently decode every remaining member in a single
+	// call instead of stopping at this one's boundary.
+	gzr.Multistream(false)
+
+	data, err := ioutil.ReadAll(gzr)
+	if err != nil {
+		return nil, err
+	}
+	if err := gzr.Close(); err != nil {
+		return nil, err
+	}
+
+	gr.compressedOffset = compStart
+	gr.compressedLength = gr.cr.n - compStart
+	gr.offset = gr.uncompressedPos
+	gr.uncompressedPos += int64(len(data))
+
+	member, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return member.Read()
+}
+
+// Offset returns the uncompressed byte offset of the most recently
+// returned Record, as if the decompressed members were concatenated
+// into one continuous WARC stream.
+func (gr *GzipReader) Offset() int64 { return gr.offset }
+
+// Pos returns the total number of uncompressed bytes produced so far
+// across every member read. Once Read has returned io.EOF, this is the
+// length of the fully decompressed stream.
+func (gr *GzipReader) Pos() int64 { return gr.uncompressedPos }
+
+// CompressedOffset returns the byte offset, within the gzip stream,
+// of the member containing the most recently returned Record.
+func (gr *GzipReader) CompressedOffset() int64 { return gr.compressedOffset }
+
+// CompressedLength returns the compressed length, in bytes, of the
+// gzip member containing the most recently returned Record.
+func (gr *GzipReader) CompressedLength() int64 { return gr.compressedLength }
+
+// byteReader adapts an io.Reader to additionally implement ReadByte
+// without any read-ahead buffering of its own, so that compress/gzip
+// and compress/flate (both of which skip their internal buffering when
+// given a reader that already implements ReadByte) consume exactly the
+// bytes of the current gzip member and nothing from the next one. That
+// precision is what lets GzipReader report an accurate compressed
+// offset/length per record.
+type byteReader struct {
+	r io.Reader
+}
+
+func (b *byteReader) Read(p []byte) (int, error) {
+	return b.r.Read(p)
+}
+
+func (b *byteReader) ReadByte() (byte, error) {
+	var buf [1]byte
+	_, err := io.ReadFull(b.r, buf[:])
+	return buf[0], err
+}
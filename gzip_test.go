@@ -0,0 +1,125 @@
+package warc
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func testRecords() Records {
+	return Records{
+		{
+			Version: "WARC/1.0",
+			Headers: map[string]string{
+				warc_type:       RecordTypeResource.String(),
+				warc_record_id:  testRecordId,
+				warc_target_uri: "http://example.com/a",
+				warc_date:       "2000-01-01T00:00:00Z",
+				content_type:    "text/plain",
+				content_length:  "5",
+			},
+			Content: newBytesBlock([]byte("alpha"), "", ""),
+		},
+		{
+			Version: "WARC/1.0",
+			Headers: map[string]string{
+				warc_type:       RecordTypeResource.String(),
+				warc_record_id:  testRecordId,
+				warc_target_uri: "http://example.com/b",
+				warc_date:       "2000-01-01T00:00:00Z",
+				content_type:    "text/plain",
+				content_length:  "4",
+			},
+			Content: newBytesBlock([]byte("beta"), "", ""),
+		},
+	}
+}
+
+func TestGzipWriteReadRoundTrip(t *testing.T) {
+	recs := testRecords()
+
+	var gzFile bytes.Buffer
+	gw := NewGzipWriter(&gzFile)
+	if err := gw.WriteRecords(recs); err != nil {
+		t.Fatal(err)
+	}
+
+	// Plain (uncompressed) round trip, for comparison.
+	var plainFile bytes.Buffer
+	if err := WriteRecords(&plainFile, recs); err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]byte{[]byte("alpha"), []byte("beta")}
+
+	for name, data := range map[string][]byte{"gzip": gzFile.Bytes(), "plain": plainFile.Bytes()} {
+		rdr, err := NewReader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("%s: %s", name, err.Error())
+		}
+		got, err := rdr.ReadAll()
+		if err != nil {
+			t.Fatalf("%s: %s", name, err.Error())
+		}
+		if len(got) != len(recs) {
+			t.Fatalf("%s: expected %d records, got %d", name, len(recs), len(got))
+		}
+		for i := range got {
+			rc, err := got[i].Content.RawBytes()
+			if err != nil {
+				t.Fatalf("%s: %s", name, err.Error())
+			}
+			gotBytes, err := ioutil.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				t.Fatalf("%s: %s", name, err.Error())
+			}
+			if !bytes.Equal(gotBytes, want[i]) {
+				t.Errorf("%s: record %d content = %q, want %q", name, i, gotBytes, want[i])
+			}
+		}
+	}
+}
+
+func TestGzipSeekToCompressedOffset(t *testing.T) {
+	recs := testRecords()
+
+	var gzFile bytes.Buffer
+	gw := NewGzipWriter(&gzFile)
+	if err := gw.WriteRecords(recs); err != nil {
+		t.Fatal(err)
+	}
+	data := gzFile.Bytes()
+
+	rdr, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := rdr.Read(); err != nil {
+		t.Fatal(err)
+	}
+	firstOffset := rdr.CompressedOffset()
+
+	if _, err := rdr.Read(); err != nil {
+		t.Fatal(err)
+	}
+	secondOffset := rdr.CompressedOffset()
+	if secondOffset <= firstOffset {
+		t.Fatalf("expected compressed offsets to advance, got %d then %d", firstOffset, secondOffset)
+	}
+
+	// A reader started directly at the second member's compressed
+	// offset should decode that member's record on its own.
+	seeked, err := NewReader(bytes.NewReader(data[secondOffset:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec, err := seeked.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Headers[warc_target_uri] != "http://example.com/b" {
+		t.Errorf("unexpected record at seeked offset: %s", rec.Headers[warc_target_uri])
+	}
+}
@@ -0,0 +1,274 @@
+package warc
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// warcVersionPrefix is the leading token of a record's version line, eg:
+// "WARC/1.0".
+const warcVersionPrefix = "WARC/"
+
+// Reader reads a sequence of Records from a WARC stream. Each Record's
+// Content block streams lazily from the underlying io.Reader: calling
+// Read before the previous Record's block has been fully consumed skips
+// the remaining, unread bytes of that block before parsing the next
+// record.
+//
+// NewReader autodetects a gzipped WARC file (a ".warc.gz", which per
+// spec is a concatenation of independently gzipped members, one per
+// record) and transparently reads one record per member in that case;
+// see GzipReader.
+type Reader struct {
+	cr     *countingReader
+	br     *bufio.Reader
+	cur    io.Reader // the unread remainder of the most recently returned Record's block, if any
+	offset int64     // byte offset, within r, of the most recently returned Record
+	gz     *GzipReader
+}
+
+// NewReader constructs a Reader that parses records from r, sniffing
+// for the gzip magic bytes to decide between plain and per-record-gzip
+// member parsing.
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if len(magic) == 2 && magic[0] == gzipMagic0 && magic[1] == gzipMagic1 {
+		gz, err := NewGzipReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return &Reader{gz: gz}, nil
+	}
+
+	cr := &countingReader{r: br}
+	return &Reader{cr: cr, br: bufio.NewReader(cr)}, nil
+}
+
+// countingReader wraps an io.Reader, counting the total number of bytes
+// read from it so far.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Offset returns the byte offset, within the stream passed to
+// NewReader, at which the most recently returned Record began. For a
+// gzipped WARC file this is the uncompressed offset; see
+// GzipReader.CompressedOffset for the compressed one.
+func (rdr *Reader) Offset() int64 {
+	if rdr.gz != nil {
+		return rdr.gz.Offset()
+	}
+	return rdr.offset
+}
+
+// Pos returns the total number of (uncompressed) bytes consumed from
+// the underlying stream so far, not counting bytes read ahead into the
+// Reader's buffer but not yet parsed.
+// Once Read has returned io.EOF, Pos reports the length of the whole
+// stream.
+func (rdr *Reader) Pos() int64 {
+	if rdr.gz != nil {
+		return rdr.gz.Pos()
+	}
+	return rdr.cr.n - int64(rdr.br.Buffered())
+}
+
+// CompressedOffset returns the compressed byte offset, within the
+// stream passed to NewReader, of the gzip member containing the most
+// recently returned Record. It returns -1 if the stream isn't gzipped.
+func (rdr *Reader) CompressedOffset() int64 {
+	if rdr.gz == nil {
+		return -1
+	}
+	return rdr.gz.CompressedOffset()
+}
+
+// CompressedLength returns the compressed length, in bytes, of the
+// gzip member containing the most recently returned Record. It returns
+// -1 if the stream isn't gzipped.
+func (rdr *Reader) CompressedLength() int64 {
+	if rdr.gz == nil {
+		return -1
+	}
+	return rdr.gz.CompressedLength()
+}
+
+// newBufioReader wraps r in a *bufio.Reader, avoiding a redundant wrap
+// when r is already buffered.
+func newBufioReader(r io.Reader) *bufio.Reader {
+	if br, ok := r.(*bufio.Reader); ok {
+		return br
+	}
+	return bufio.NewReader(r)
+}
+
+// Read returns the next Record in the stream, or io.EOF once the
+// stream is exhausted.
+func (rdr *Reader) Read() (*Record, error) {
+	if rdr.gz != nil {
+		return rdr.gz.Read()
+	}
+
+	if rdr.cur != nil {
+		if _, err := io.Copy(ioutil.Discard, rdr.cur); err != nil {
+			return nil, err
+		}
+		rdr.cur = nil
+	}
+
+	version, offset, err := rdr.readVersionLine()
+	if err != nil {
+		return nil, err
+	}
+	rdr.offset = offset
+
+	headers, err := rdr.readHeaders()
+	if err != nil {
+		return nil, err
+	}
+
+	size, err := strconv.ParseInt(headers[content_length], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s header %q: %s", content_length, headers[content_length], err.Error())
+	}
+
+	blockSrc := io.LimitReader(rdr.br, size)
+	rdr.cur = blockSrc
+
+	blk := newBlock(blockSrc, size, headers)
+
+	return &Record{
+		Version: version,
+		Headers: headers,
+		Content: blk,
+	}, nil
+}
+
+// ReadAll reads every remaining Record from the stream. Unlike Read,
+// each returned Record's Content is cached before the next Record is
+// parsed, so every Record in the result can still be read after
+// ReadAll returns.
+func (rdr *Reader) ReadAll() (Records, error) {
+	var recs Records
+	for {
+		rec, err := rdr.Read()
+		if err == io.EOF {
+			return recs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if rec.Content != nil {
+			if err := rec.Content.Cache(); err != nil {
+				return nil, err
+			}
+		}
+		recs = append(recs, *rec)
+	}
+}
+
+// readVersionLine consumes blank lines left over between records and
+// returns the version token off the "WARC/x.y" line, along with the
+// byte offset at which that line began.
+func (rdr *Reader) readVersionLine() (string, int64, error) {
+	for {
+		pos := rdr.Pos()
+		line, err := rdr.br.ReadString('\n')
+		if err != nil {
+			return "", 0, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, warcVersionPrefix) {
+			return "", 0, fmt.Errorf("expected WARC version line, got %q", line)
+		}
+		return line, pos, nil
+	}
+}
+
+// readHeaders reads "Name: value" lines up to the blank line that
+// terminates the header block, normalizing each name to its canonical
+// WARC casing where one is known.
+func (rdr *Reader) readHeaders() (map[string]string, error) {
+	headers := map[string]string{}
+	for {
+		line, err := rdr.br.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			return headers, nil
+		}
+		sep := strings.Index(line, ":")
+		if sep < 0 {
+			return nil, fmt.Errorf("malformed header line %q", line)
+		}
+		name := canonicalHeaderName(strings.TrimSpace(line[:sep]))
+		headers[name] = strings.TrimSpace(line[sep+1:])
+	}
+}
+
+// canonicalHeaderNames maps the lowercased form of every header name
+// this package knows about to its canonical casing, so headers read
+// off the wire (which vary: "Warc-Date", "WARC-DATE", ...) compare
+// equal regardless of how the writing tool cased them.
+var canonicalHeaderNames = func() map[string]string {
+	m := map[string]string{}
+	for _, name := range []string{
+		warc_type, warc_record_id, warc_date, warc_target_uri, warc_filename,
+		warc_refers_to, warc_refers_to_target_uri, warc_refers_to_date,
+		warc_concurrent_to, warc_block_digest, warc_payload_digest,
+		warc_ip_address, warc_profile, warc_segment_number,
+		warc_segment_origin_id, warc_segment_total_length,
+		content_type, content_length,
+	} {
+		m[strings.ToLower(name)] = name
+	}
+	return m
+}()
+
+func canonicalHeaderName(name string) string {
+	if canon, ok := canonicalHeaderNames[strings.ToLower(name)]; ok {
+		return canon
+	}
+	return name
+}
+
+// newBlock constructs the Block implementation appropriate for a
+// record's Content-Type header, reading lazily from src.
+func newBlock(src io.Reader, size int64, headers map[string]string) Block {
+	open := func() (io.Reader, error) { return src, nil }
+	blockDigest := headers[warc_block_digest]
+	payloadDigest := headers[warc_payload_digest]
+
+	ct := headers[content_type]
+	switch {
+	case strings.HasPrefix(ct, "application/http"):
+		if strings.Contains(ct, "msgtype=request") {
+			return newHTTPRequestBlock(open, size, blockDigest, payloadDigest)
+		}
+		return newHTTPResponseBlock(open, size, blockDigest, payloadDigest)
+	case strings.HasPrefix(ct, "application/warc-fields"):
+		return newWarcFieldsBlock(open, size, blockDigest, payloadDigest)
+	default:
+		return newGenericBlock(open, size, blockDigest, payloadDigest)
+	}
+}
@@ -3,17 +3,21 @@ package warc
 import (
 	"bytes"
 	"io"
+	"strconv"
 	"time"
 )
 
 // A Record consists of a version indicator (eg: WARC/1.0), zero or more headers,
-// and possibly a content block.
+// and a content block.
 // Upgrades to specific types of records can be done using type assertions
 // and/or the Type method.
 type Record struct {
 	Version string
 	Headers map[string]string
-	Content []byte
+	// Content is the record's content block. It streams lazily when the
+	// Record comes from a Reader, so it can only be read once unless
+	// Content.Cache is called first.
+	Content Block
 }
 
 // Return the type of record
@@ -26,20 +30,47 @@ func (r *Record) Id() string {
 	return r.Headers[warc_record_id]
 }
 
-// Datestamp of record creation
+// Datestamp of record creation, parsed from the WARC-Date header. The
+// zero time is returned if the header is missing or malformed.
 func (r *Record) Date() time.Time {
-	// TODO
-	return time.Now()
+	t, err := time.Parse(time.RFC3339, r.Headers[warc_date])
+	if err != nil {
+		return time.Time{}
+	}
+	return t
 }
 
-// Length of content block in bytes
+// Length of content block in bytes, parsed from the Content-Length
+// header. If the header is missing or malformed, falls back to the
+// Content block's own reported size.
 func (r *Record) ContentLength() int64 {
-	// TODO
+	if n, err := strconv.ParseInt(r.Headers[content_length], 10, 64); err == nil {
+		return n
+	}
+	if r.Content != nil {
+		return r.Content.Size()
+	}
 	return 0
 }
 
-// Write this record to a given writer
+// Write this record to a given writer. If the record's headers omit
+// WARC-Block-Digest or WARC-Payload-Digest, they are computed (using
+// defaultDigestAlgorithm) and added before the headers are written; this
+// requires caching the content block, since the WARC format requires
+// the digest headers to precede the block they describe. A continuation
+// record is given no payload digest (see ensureDigests).
 func (r *Record) Write(w io.Writer) error {
+	if r.Content != nil {
+		if r.Headers[warc_block_digest] == "" || r.Headers[warc_payload_digest] == "" {
+			if err := r.Content.Cache(); err != nil {
+				return err
+			}
+			if err := r.ensureDigests(defaultDigestAlgorithm); err != nil {
+				return err
+			}
+		}
+	}
+
 	if err := writeHeader(w, r); err != nil {
 		return err
 	}
@@ -0,0 +1,233 @@
+package warc
+
+import (
+	"bufio"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// SegmentingWriter wraps an io.Writer, splitting any record whose block
+// would exceed MaxRecordSize into a series of WARC record segments: the
+// first segment keeps the record's original WARC-Type and carries
+// WARC-Segment-Number: 1, and each subsequent segment is written as a
+// 'continuation' record referencing the first via
+// WARC-Segment-Origin-ID. The final continuation additionally carries
+// WARC-Segment-Total-Length. Records at or under MaxRecordSize are
+// written unmodified.
+type SegmentingWriter struct {
+	w             io.Writer
+	MaxRecordSize int64
+}
+
+// NewSegmentingWriter constructs a SegmentingWriter that splits any
+// record whose block exceeds maxRecordSize in bytes.
+func NewSegmentingWriter(w io.Writer, maxRecordSize int64) *SegmentingWriter {
+	return &SegmentingWriter{w: w, MaxRecordSize: maxRecordSize}
+}
+
+// WriteRecord writes r to the underlying writer, segmenting it first if
+// its block exceeds MaxRecordSize.
+func (sw *SegmentingWriter) WriteRecord(r *Record) error {
+	if r.Content == nil || sw.MaxRecordSize <= 0 || r.ContentLength() <= sw.MaxRecordSize {
+		return r.Write(sw.w)
+	}
+
+	rc, err := r.Content.RawBytes()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	originID := r.Id()
+	totalLength := r.ContentLength()
+
+	br := bufio.NewReaderSize(rc, int(sw.MaxRecordSize))
+	for segNum := 1; ; segNum++ {
+		buf := make([]byte, sw.MaxRecordSize)
+		n, rerr := io.ReadFull(br, buf)
+		if rerr != nil && rerr != io.ErrUnexpectedEOF && rerr != io.EOF {
+			return rerr
+		}
+		buf = buf[:n]
+
+		_, peekErr := br.Peek(1)
+		last := peekErr != nil
+
+		seg := &Record{Version: r.Version, Headers: copyHeaders(r.Headers)}
+		seg.Headers[content_length] = strconv.FormatInt(int64(len(buf)), 10)
+		// Block digests are per-segment; drop the whole block's digest so
+		// that one is computed below for this segment alone.
+		delete(seg.Headers, warc_block_digest)
+
+		if segNum == 1 {
+			seg.Headers[warc_segment_number] = "1"
+		} else {
+			seg.Headers[warc_type] = RecordTypeContinuation.String()
+			seg.Headers[warc_record_id] = newRecordID()
+			seg.Headers[warc_segment_number] = strconv.Itoa(segNum)
+			seg.Headers[warc_segment_origin_id] = originID
+			// The payload digest describes the reassembled whole and was
+			// only meaningful on the first segment.
+			delete(seg.Headers, warc_payload_digest)
+		}
+		if last && segNum > 1 {
+			seg.Headers[warc_segment_total_length] = strconv.FormatInt(totalLength, 10)
+		}
+
+		seg.Content = newBytesBlock(buf, "", seg.Headers[warc_payload_digest])
+
+		// Compute the per-segment block digest directly rather than
+		// through Record.Write, since that would also try to fill in a
+		// payload digest for continuation segments, which don't carry
+		// one: the payload digest describes the reassembled whole, not
+		// any individual segment.
+		rawBytes, err := seg.Content.RawBytes()
+		if err != nil {
+			return err
+		}
+		blockDigest, err := computeDigest(defaultDigestAlgorithm, rawBytes)
+		rawBytes.Close()
+		if err != nil {
+			return err
+		}
+		seg.Headers[warc_block_digest] = blockDigest
+
+		if err := writeHeader(sw.w, seg); err != nil {
+			return err
+		}
+		if err := writeBlock(sw.w, seg.Content); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// copyHeaders returns a shallow copy of a record's header map.
+func copyHeaders(h map[string]string) map[string]string {
+	out := make(map[string]string, len(h))
+	for k, v := range h {
+		out[k] = v
+	}
+	return out
+}
+
+// newRecordID generates a fresh WARC-Record-ID in the "<urn:uuid:...>"
+// form used throughout this package's test fixtures.
+func newRecordID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// ReassemblingReader wraps one or more Readers, transparently stitching
+// WARC record segments (see SegmentingWriter) back together. A
+// segment's continuation records need not come from the same stream as
+// its first segment: segmenting exists specifically so a record can be
+// split across separate output files once a size limit is hit, so
+// ReassemblingReader reads its Readers in order, one after another, as
+// if they were a single concatenated stream. Non-segmented records
+// pass through unchanged; a segmented record is only returned once its
+// final continuation has been read, as a single logical Record whose
+// Content streams across all of its segments in order.
+type ReassemblingReader struct {
+	rdrs    []*Reader
+	pending map[string][]*Record
+}
+
+// NewReassemblingReader constructs a ReassemblingReader reading
+// segmented records from rdrs, in order.
+func NewReassemblingReader(rdrs ...*Reader) *ReassemblingReader {
+	return &ReassemblingReader{rdrs: rdrs, pending: map[string][]*Record{}}
+}
+
+// Read returns the next logical Record, reassembling segments as
+// needed, advancing to the next Reader once the current one is
+// exhausted. Continuation records belonging to a series still in
+// progress are consumed internally and do not produce a Read result of
+// their own.
+func (rr *ReassemblingReader) Read() (*Record, error) {
+	for {
+		if len(rr.rdrs) == 0 {
+			return nil, io.EOF
+		}
+
+		rec, err := rr.rdrs[0].Read()
+		if err == io.EOF {
+			rr.rdrs = rr.rdrs[1:]
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if rec.Headers[warc_segment_number] == "" {
+			return rec, nil
+		}
+
+		if err := rec.Content.Cache(); err != nil {
+			return nil, err
+		}
+
+		if rec.Type() != RecordTypeContinuation {
+			// First segment of a series: stash it and keep reading.
+			rr.pending[rec.Id()] = []*Record{rec}
+			continue
+		}
+
+		originID := rec.Headers[warc_segment_origin_id]
+		segs, ok := rr.pending[originID]
+		if !ok {
+			return nil, fmt.Errorf("continuation record %s refers to unknown origin %s", rec.Id(), originID)
+		}
+		segs = append(segs, rec)
+		rr.pending[originID] = segs
+
+		if rec.Headers[warc_segment_total_length] == "" {
+			continue
+		}
+
+		delete(rr.pending, originID)
+		return reassembleSegments(segs)
+	}
+}
+
+// reassembleSegments combines a complete series of segment records
+// (first segment through final continuation, in order) into a single
+// logical Record.
+func reassembleSegments(segs []*Record) (*Record, error) {
+	first := segs[0]
+	last := segs[len(segs)-1]
+
+	totalLength, err := strconv.ParseInt(last.Headers[warc_segment_total_length], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s header %q: %s", warc_segment_total_length, last.Headers[warc_segment_total_length], err.Error())
+	}
+
+	readers := make([]io.Reader, 0, len(segs))
+	for _, s := range segs {
+		rc, err := s.Content.RawBytes()
+		if err != nil {
+			return nil, err
+		}
+		readers = append(readers, rc)
+	}
+
+	headers := copyHeaders(first.Headers)
+	delete(headers, warc_segment_number)
+	delete(headers, warc_segment_total_length)
+	delete(headers, warc_block_digest)
+	headers[content_length] = strconv.FormatInt(totalLength, 10)
+
+	combined := &Record{
+		Version: first.Version,
+		Headers: headers,
+	}
+	combined.Content = newBlock(io.MultiReader(readers...), totalLength, headers)
+	return combined, nil
+}
@@ -0,0 +1,160 @@
+package warc
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func TestSegmentingRoundTrip(t *testing.T) {
+	original := []byte("0123456789abcdefghij")
+	rec := &Record{
+		Version: "WARC/1.0",
+		Headers: map[string]string{
+			warc_type:       RecordTypeResource.String(),
+			warc_record_id:  testRecordId,
+			warc_target_uri: "http://example.com/",
+			warc_date:       "2000-01-01T00:00:00Z",
+			content_type:    "text/plain",
+			content_length:  "20",
+		},
+		Content: newBytesBlock(original, "", ""),
+	}
+
+	buf := &bytes.Buffer{}
+	sw := NewSegmentingWriter(buf, 8)
+	if err := sw.WriteRecord(rec); err != nil {
+		t.Fatal(err)
+	}
+
+	data := append([]byte(nil), buf.Bytes()...)
+
+	rdr, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	segments, err := rdr.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segments) != 3 {
+		t.Fatalf("expected 3 segments for a 20 byte record split at 8 bytes, got %d", len(segments))
+	}
+
+	rdr2, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := NewReassemblingReader(rdr2)
+
+	out, err := rr.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rc, err := out.Content.RawBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+
+	got, err := ioutil.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, original) {
+		t.Errorf("reassembled content = %q, want %q", got, original)
+	}
+}
+
+func TestReassemblingReaderAcrossFiles(t *testing.T) {
+	original := []byte("0123456789abcdefghij")
+	rec := &Record{
+		Version: "WARC/1.0",
+		Headers: map[string]string{
+			warc_type:       RecordTypeResource.String(),
+			warc_record_id:  testRecordId,
+			warc_target_uri: "http://example.com/",
+			warc_date:       "2000-01-01T00:00:00Z",
+			content_type:    "text/plain",
+			content_length:  "20",
+		},
+		Content: newBytesBlock(original, "", ""),
+	}
+
+	buf := &bytes.Buffer{}
+	sw := NewSegmentingWriter(buf, 8)
+	if err := sw.WriteRecord(rec); err != nil {
+		t.Fatal(err)
+	}
+
+	rdr0, err := NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	segments, err := rdr0.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segments) != 3 {
+		t.Fatalf("expected 3 segments for a 20 byte record split at 8 bytes, got %d", len(segments))
+	}
+
+	// Split the segments across separate WARC files, as segmentation is
+	// meant to allow: the first segment in one file, the continuations
+	// in another.
+	var file1, file2 bytes.Buffer
+	if err := WriteRecords(&file1, segments[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteRecords(&file2, segments[1:]); err != nil {
+		t.Fatal(err)
+	}
+
+	// Re-serializing a continuation record through the ordinary Write
+	// path must not regain a WARC-Payload-Digest: that digest describes
+	// the reassembled whole, not any individual segment.
+	rdrCheck, err := NewReader(bytes.NewReader(file2.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	written, err := rdrCheck.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range written {
+		if written[i].Type() == RecordTypeContinuation && written[i].Headers[warc_payload_digest] != "" {
+			t.Errorf("continuation segment %d has a payload digest: %s", i, written[i].Headers[warc_payload_digest])
+		}
+	}
+
+	rdr1, err := NewReader(bytes.NewReader(file1.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rdr2, err := NewReader(bytes.NewReader(file2.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := NewReassemblingReader(rdr1, rdr2)
+	out, err := rr.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rc, err := out.Content.RawBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+
+	got, err := ioutil.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, original) {
+		t.Errorf("reassembled content = %q, want %q", got, original)
+	}
+}
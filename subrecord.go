@@ -0,0 +1,211 @@
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HTTPResponse parses the record's block as an "application/http;
+// msgtype=response" payload, returning the underlying *http.Response.
+// The returned response's Body reads lazily from the record's block and
+// closing it also closes the block's reader.
+func (r *Record) HTTPResponse() (*http.Response, error) {
+	if ct := r.Headers[content_type]; !strings.HasPrefix(ct, "application/http") {
+		return nil, fmt.Errorf("record Content-Type %q is not application/http", ct)
+	}
+
+	rc, err := r.Content.RawBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(rc), nil)
+	if err != nil {
+		rc.Close()
+		return nil, fmt.Errorf("parsing http response: %s", err.Error())
+	}
+	resp.Body = &chainReadCloser{Reader: resp.Body, closers: []io.Closer{resp.Body, rc}}
+	return resp, nil
+}
+
+// HTTPRequest parses the record's block as an "application/http;
+// msgtype=request" payload, returning the underlying *http.Request. The
+// returned request's Body reads lazily from the record's block and
+// closing it also closes the block's reader.
+func (r *Record) HTTPRequest() (*http.Request, error) {
+	if ct := r.Headers[content_type]; !strings.HasPrefix(ct, "application/http") {
+		return nil, fmt.Errorf("record Content-Type %q is not application/http", ct)
+	}
+
+	rc, err := r.Content.RawBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.ReadRequest(bufio.NewReader(rc))
+	if err != nil {
+		rc.Close()
+		return nil, fmt.Errorf("parsing http request: %s", err.Error())
+	}
+	req.Body = &chainReadCloser{Reader: req.Body, closers: []io.Closer{req.Body, rc}}
+	return req, nil
+}
+
+// DNSAnswer is a single BIND-style resource record line from a DNS
+// record's block, eg:
+//
+//	google.com.     185 IN  A   209.148.113.239
+type DNSAnswer struct {
+	Name  string
+	TTL   int
+	Class string
+	Type  string
+	Value string
+}
+
+// DNSAnswers parses the record's block as a DNS record: a timestamp
+// line (YYYYMMDDHHMMSS) followed by one BIND-style resource record per
+// line, as produced by a 'dns:' scheme capture.
+func (r *Record) DNSAnswers() ([]DNSAnswer, error) {
+	rc, err := r.Content.RawBytes()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	scanner := bufio.NewScanner(rc)
+
+	if !scanner.Scan() {
+		return nil, scanner.Err()
+	}
+	// scanner.Text() here is the capture timestamp line; DNSAnswers
+	// only reports the answers that follow it.
+
+	var answers []DNSAnswer
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			return nil, fmt.Errorf("malformed DNS answer line: %q", line)
+		}
+		ttl, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("malformed DNS answer TTL in %q: %s", line, err.Error())
+		}
+		answers = append(answers, DNSAnswer{
+			Name:  fields[0],
+			TTL:   ttl,
+			Class: fields[2],
+			Type:  fields[3],
+			Value: strings.Join(fields[4:], " "),
+		})
+	}
+	return answers, scanner.Err()
+}
+
+// NewHTTPResponseRecord builds a 'response' Record from an
+// *http.Response, setting Content-Type, WARC-Type, WARC-Target-URI,
+// WARC-Date, and freshly computed digests.
+func NewHTTPResponseRecord(targetURI string, resp *http.Response) (*Record, error) {
+	buf := &bytes.Buffer{}
+	if err := resp.Write(buf); err != nil {
+		return nil, fmt.Errorf("serializing http response: %s", err.Error())
+	}
+
+	rec := &Record{
+		Version: "WARC/1.0",
+		Headers: map[string]string{
+			warc_type:       RecordTypeResponse.String(),
+			warc_record_id:  newRecordID(),
+			warc_target_uri: targetURI,
+			warc_date:       time.Now().UTC().Format(time.RFC3339),
+			content_type:    "application/http; msgtype=response",
+			content_length:  headerContentLength(int64(buf.Len())),
+		},
+		Content: &httpResponseBlock{genericBlock: newBytesBlock(buf.Bytes(), "", "")},
+	}
+	if err := rec.ensureDigests(defaultDigestAlgorithm); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// NewHTTPRequestRecord builds a 'request' Record from an *http.Request,
+// setting Content-Type, WARC-Type, WARC-Target-URI, WARC-Date, and
+// freshly computed digests.
+func NewHTTPRequestRecord(targetURI string, req *http.Request) (*Record, error) {
+	buf := &bytes.Buffer{}
+	if err := req.Write(buf); err != nil {
+		return nil, fmt.Errorf("serializing http request: %s", err.Error())
+	}
+
+	rec := &Record{
+		Version: "WARC/1.0",
+		Headers: map[string]string{
+			warc_type:       RecordTypeRequest.String(),
+			warc_record_id:  newRecordID(),
+			warc_target_uri: targetURI,
+			warc_date:       time.Now().UTC().Format(time.RFC3339),
+			content_type:    "application/http; msgtype=request",
+			content_length:  headerContentLength(int64(buf.Len())),
+		},
+		Content: &httpRequestBlock{genericBlock: newBytesBlock(buf.Bytes(), "", "")},
+	}
+	if err := rec.ensureDigests(defaultDigestAlgorithm); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// mandatoryHeaders are the WARC headers required on every record type,
+// per the ISO 28500 record header field table.
+var mandatoryHeaders = []string{warc_type, warc_record_id, warc_date, content_length}
+
+// targetURIRequiredTypes are the record types whose ISO 28500 profile
+// requires a WARC-Target-URI.
+var targetURIRequiredTypes = map[RecordType]bool{
+	RecordTypeResponse:   true,
+	RecordTypeResource:   true,
+	RecordTypeRequest:    true,
+	RecordTypeRevisit:    true,
+	RecordTypeConversion: true,
+}
+
+// Validate checks that r carries the WARC headers its record type is
+// required to have, per the ISO 28500 profile. It does not validate
+// header values beyond their presence.
+func (r *Record) Validate() error {
+	var missing []string
+
+	for _, h := range mandatoryHeaders {
+		if r.Headers[h] == "" {
+			missing = append(missing, h)
+		}
+	}
+
+	if targetURIRequiredTypes[r.Type()] && r.Headers[warc_target_uri] == "" {
+		missing = append(missing, warc_target_uri)
+	}
+
+	if r.Type() == RecordTypeContinuation {
+		for _, h := range []string{warc_segment_origin_id, warc_segment_number} {
+			if r.Headers[h] == "" {
+				missing = append(missing, h)
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("record missing mandatory header(s): %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package warc
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDNSAnswers(t *testing.T) {
+	rdr, err := NewReader(bytes.NewReader(DNS_RESPONSE_RECORD))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec, err := rdr.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	answers, err := rec.DNSAnswers()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(answers) != 3 {
+		t.Fatalf("expected 3 DNS answers, got %d", len(answers))
+	}
+
+	want := DNSAnswer{Name: "google.com.", TTL: 185, Class: "IN", Type: "A", Value: "209.148.113.239"}
+	if answers[0] != want {
+		t.Errorf("first answer = %+v, want %+v", answers[0], want)
+	}
+}
+
+func TestNewHTTPResponseRecordPayloadDigestIsBodyOnly(t *testing.T) {
+	resp := &http.Response{
+		StatusCode: 200,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{"Content-Type": []string{"text/plain"}},
+		Body:       ioutil.NopCloser(strings.NewReader("some body text")),
+	}
+
+	rec, err := NewHTTPResponseRecord("http://example.com/", resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload, err := rec.Content.PayloadBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer payload.Close()
+
+	got, err := ioutil.ReadAll(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "some body text" {
+		t.Errorf("PayloadBytes() = %q, want the entity body only", got)
+	}
+
+	wantDigest, err := computeDigest(defaultDigestAlgorithm, strings.NewReader("some body text"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Headers[warc_payload_digest] != wantDigest {
+		t.Errorf("WARC-Payload-Digest = %s, want %s", rec.Headers[warc_payload_digest], wantDigest)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	rec := &Record{
+		Headers: map[string]string{
+			warc_type:      RecordTypeResponse.String(),
+			warc_record_id: testRecordId,
+			warc_date:      "2000-01-01T00:00:00Z",
+			content_length: "0",
+		},
+	}
+
+	if err := rec.Validate(); err == nil {
+		t.Error("expected a response record without WARC-Target-URI to fail validation")
+	}
+
+	rec.Headers[warc_target_uri] = "http://example.com/"
+	if err := rec.Validate(); err != nil {
+		t.Errorf("expected a fully-populated response record to validate, got: %s", err.Error())
+	}
+}
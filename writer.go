@@ -0,0 +1,67 @@
+package warc
+
+import (
+	"fmt"
+	"io"
+	"net/textproto"
+	"sort"
+	"strconv"
+)
+
+// WriteRecords writes each record in recs to w in order.
+func WriteRecords(w io.Writer, recs Records) error {
+	for i := range recs {
+		if err := recs[i].Write(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeHeader writes a record's version line and headers, followed by
+// the blank line that separates headers from the content block.
+func writeHeader(w io.Writer, r *Record) error {
+	if _, err := fmt.Fprintf(w, "%s\r\n", r.Version); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(r.Headers))
+	for name := range r.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if _, err := fmt.Fprintf(w, "%s: %s\r\n", textproto.CanonicalMIMEHeaderKey(name), r.Headers[name]); err != nil {
+			return err
+		}
+	}
+
+	_, err := io.WriteString(w, "\r\n")
+	return err
+}
+
+// writeBlock streams a record's content block to w, followed by the
+// trailing CRLF CRLF that terminates a WARC record.
+func writeBlock(w io.Writer, blk Block) error {
+	if blk != nil {
+		rc, err := blk.RawBytes()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+
+		if _, err := io.Copy(w, rc); err != nil {
+			return err
+		}
+	}
+
+	_, err := io.WriteString(w, "\r\n\r\n")
+	return err
+}
+
+// headerContentLength returns the record's Content-Length header as
+// reported on the block, formatted for inclusion in r.Headers.
+func headerContentLength(size int64) string {
+	return strconv.FormatInt(size, 10)
+}
@@ -65,20 +65,20 @@ func TestWarcWrite(t *testing.T) {
 }
 
 func TestWarcinfoRecord(t *testing.T) {
+	content := []byte("software: recorder test\r\n" +
+		"format: WARC File Format 1.0\r\n" +
+		"json-metadata: {\"foo\": \"bar\"}\r\n")
 	rec := &Record{
-		Format: RecordFormatWarc,
-		Type:   RecordTypeWarcInfo,
+		Version: "WARC/1.0",
 		Headers: map[string]string{
-			warcRecordId:  testRecordId,
-			warcType:      RecordTypeWarcInfo.String(),
-			warcFilename:  "testfile.warc.gz",
-			warcDate:      "2000-01-01T00:00:00Z",
-			contentType:   "application/warc-fields",
-			contentLength: "86",
+			warc_record_id: testRecordId,
+			warc_type:      RecordTypeWarcInfo.String(),
+			warc_filename:  "testfile.warc.gz",
+			warc_date:      "2000-01-01T00:00:00Z",
+			content_type:   "application/warc-fields",
+			content_length: "86",
 		},
-		Content: bytes.NewBuffer([]byte("software: recorder test\r\n" +
-			"format: WARC File Format 1.0\r\n" +
-			"json-metadata: {\"foo\": \"bar\"}\r\n")),
+		Content: newBytesBlock(content, "", ""),
 	}
 
 	if err := testWriteRecord(rec, WARCINFO_RECORD); err != nil {
@@ -87,23 +87,23 @@ func TestWarcinfoRecord(t *testing.T) {
 }
 
 func TestRequestRecord(t *testing.T) {
+	content := []byte("GET / HTTP/1.0\r\n" +
+		"User-Agent: foo\r\n" +
+		"Host: example.com\r\n" +
+		"\r\n")
 	rec := &Record{
-		Format: RecordFormatWarc,
-		Type:   RecordTypeRequest,
+		Version: "WARC/1.0",
 		Headers: map[string]string{
-			warcType:          RecordTypeRequest.String(),
-			warcRecordId:      testRecordId,
-			warcTargetUri:     "http://example.com/",
-			warcDate:          "2000-01-01T00:00:00Z",
-			warcPayloadDigest: "sha1:3I42H3S6NNFQ2MSVX7XZKYAYSCX5QBYJ",
-			warcBlockDigest:   "sha1:ONEHF6PTXPTTHE3333XHTD2X45TZ3DTO",
-			contentType:       "application/http; msgtype=request",
-			contentLength:     "54",
+			warc_type:           RecordTypeRequest.String(),
+			warc_record_id:      testRecordId,
+			warc_target_uri:     "http://example.com/",
+			warc_date:           "2000-01-01T00:00:00Z",
+			warc_payload_digest: "sha1:3I42H3S6NNFQ2MSVX7XZKYAYSCX5QBYJ",
+			warc_block_digest:   "sha1:ONEHF6PTXPTTHE3333XHTD2X45TZ3DTO",
+			content_type:        "application/http; msgtype=request",
+			content_length:      "54",
 		},
-		Content: bytes.NewBuffer([]byte("GET / HTTP/1.0\r\n" +
-			"User-Agent: foo\r\n" +
-			"Host: example.com\r\n" +
-			"\r\n")),
+		Content: newBytesBlock(content, "", ""),
 	}
 
 	if err := testWriteRecord(rec, REQUEST_RECORD); err != nil {
@@ -112,25 +112,25 @@ func TestRequestRecord(t *testing.T) {
 }
 
 func TestResponseRecord(t *testing.T) {
+	content := []byte("HTTP/1.0 200 OK\r\n" +
+		"Content-Type: text/plain; charset=\"UTF-8\"\r\n" +
+		"Custom-Header: somevalue\r\n" +
+		"\r\n" +
+		"some\n" +
+		"text")
 	rec := &Record{
-		Format: RecordFormatWarc,
-		Type:   RecordTypeResponse,
+		Version: "WARC/1.0",
 		Headers: map[string]string{
-			contentLength:     "97",
-			contentType:       "application/http; msgtype=response",
-			warcBlockDigest:   "sha1:OS3OKGCWQIJOAOC3PKXQOQFD52NECQ74",
-			warcDate:          "2000-01-01T00:00:00Z",
-			warcPayloadDigest: "sha1:B6QJ6BNJ3R4B23XXMRKZKHLPGJY2VE4O",
-			warcRecordId:      "<urn:uuid:12345678-feb0-11e6-8f83-68a86d1772ce>",
-			warcTargetUri:     "http://example.com/",
-			warcType:          RecordTypeResponse.String(),
+			content_length:      "97",
+			content_type:        "application/http; msgtype=response",
+			warc_block_digest:   "sha1:OS3OKGCWQIJOAOC3PKXQOQFD52NECQ74",
+			warc_date:           "2000-01-01T00:00:00Z",
+			warc_payload_digest: "sha1:B6QJ6BNJ3R4B23XXMRKZKHLPGJY2VE4O",
+			warc_record_id:      "<urn:uuid:12345678-feb0-11e6-8f83-68a86d1772ce>",
+			warc_target_uri:     "http://example.com/",
+			warc_type:           RecordTypeResponse.String(),
 		},
-		Content: bytes.NewBuffer([]byte("HTTP/1.0 200 OK\r\n" +
-			"Content-Type: text/plain; charset=\"UTF-8\"\r\n" +
-			"Custom-Header: somevalue\r\n" +
-			"\r\n" +
-			"some\n" +
-			"text")),
+		Content: newBytesBlock(content, "", ""),
 	}
 
 	if err := testWriteRecord(rec, RESPONSE_RECORD); err != nil {
@@ -139,8 +139,8 @@ func TestResponseRecord(t *testing.T) {
 }
 
 func testWriteRecord(r *Record, expect []byte) error {
-	if r.ContentLength() != r.Content.Len() {
-		return fmt.Errorf("Record Content-Length mistmatch: %d != %d", r.ContentLength(), r.Content.Len())
+	if r.ContentLength() != r.Content.Size() {
+		return fmt.Errorf("Record Content-Length mistmatch: %d != %d", r.ContentLength(), r.Content.Size())
 	}
 
 	buf := &bytes.Buffer{}
@@ -184,8 +184,10 @@ func validateResponse(r *Record) error {
 var WARCINFO_RECORD = []byte(`WARC/1.0\r
 Content-Length: 86\r
 Content-Type: application/warc-fields\r
+Warc-Block-Digest: sha1:GAD6P5BTZPRU57ICXEYUJZGCURZYABID\r
 Warc-Date: 2000-01-01T00:00:00Z\r
 Warc-Filename: testfile.warc.gz\r
+Warc-Payload-Digest: sha1:GAD6P5BTZPRU57ICXEYUJZGCURZYABID\r
 Warc-Record-Id: <urn:uuid:12345678-feb0-11e6-8f83-68a86d1772ce>\r
 Warc-Type: warcinfo\r
 \r